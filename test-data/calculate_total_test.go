@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// calculateTotalNaive is the original O(N) loop calculateTotal used before
+// it was closed-form, kept here only to check the new version against it.
+func calculateTotalNaive(order Order) float64 {
+	total := order.Amount
+
+	for i := 0; i < calcTotalIterations; i++ {
+		total += float64(i) * 0.001
+		total = total * calcTotalGrowthRate
+	}
+
+	fee := total * 0.029
+	total += fee
+
+	return total
+}
+
+func TestCalculateTotalEquivalence(t *testing.T) {
+	amounts := []float64{0, 0.01, 1, 42.5, 100, 999.99, 1000000, -50}
+
+	for _, amount := range amounts {
+		order := Order{Amount: amount}
+
+		got := calculateTotal(context.Background(), order)
+		want := calculateTotalNaive(order)
+
+		diff := math.Abs(got - want)
+		tolerance := 1e-6 * math.Max(math.Abs(want), 1)
+		if diff > tolerance {
+			t.Errorf("calculateTotal(%v) = %v, want %v (diff %v > tolerance %v)", amount, got, want, diff, tolerance)
+		}
+	}
+}
+
+// BenchmarkCalculateTotalNaive and BenchmarkCalculateTotal both call the raw
+// math with no tracing/pyroscope overhead, so the comparison actually shows
+// the recurrence closing to O(1) instead of being swamped by
+// instrumentation calculateTotalNaive doesn't pay for.
+func BenchmarkCalculateTotalNaive(b *testing.B) {
+	order := Order{Amount: 100}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calculateTotalNaive(order)
+	}
+}
+
+func BenchmarkCalculateTotal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calculateTotalClosed(100)
+	}
+}
@@ -0,0 +1,138 @@
+// Package flamegraph collects a CPU profile, folds it into Brendan Gregg's
+// collapsed-stack format, and renders it as an interactive SVG flamegraph
+// directly from Go -- no external flamegraph.pl dependency. It is exposed
+// over HTTP via Handler, served at /debug/flamegraph on the demo server's
+// mux.
+package flamegraph
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// Stack is one sampled call stack, root-first, with the number of profiling
+// samples it was observed in.
+type Stack struct {
+	Frames []string
+	Count  int64
+}
+
+// Collect runs a CPU profile for the given duration and returns the raw
+// sampled stacks (not yet folded/merged).
+func Collect(duration time.Duration) ([]Stack, error) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, fmt.Errorf("flamegraph: start cpu profile: %w", err)
+	}
+	time.Sleep(duration)
+	pprof.StopCPUProfile()
+
+	return stacksFromProfile(buf.Bytes())
+}
+
+func stacksFromProfile(data []byte) ([]Stack, error) {
+	prof, err := profile.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("flamegraph: parse profile: %w", err)
+	}
+
+	valueIdx := 0
+	for i, st := range prof.SampleType {
+		if st.Type == "samples" {
+			valueIdx = i
+			break
+		}
+	}
+
+	stacks := make([]Stack, 0, len(prof.Sample))
+	for _, sample := range prof.Sample {
+		frames := make([]string, 0, len(sample.Location))
+		for i := len(sample.Location) - 1; i >= 0; i-- {
+			// A location's inlined lines are listed innermost first, so walk
+			// them backwards to keep the stack root-first.
+			lines := sample.Location[i].Line
+			for j := len(lines) - 1; j >= 0; j-- {
+				line := lines[j]
+				if line.Function != nil && line.Function.Name != "" {
+					frames = append(frames, line.Function.Name)
+				}
+			}
+		}
+		if len(frames) == 0 {
+			continue
+		}
+
+		count := int64(1)
+		if valueIdx < len(sample.Value) {
+			count = sample.Value[valueIdx]
+		}
+		stacks = append(stacks, Stack{Frames: frames, Count: count})
+	}
+
+	return stacks, nil
+}
+
+// Fold merges identical stacks and renders them in Brendan Gregg's collapsed
+// format: one "func1;func2;func3 count" line per unique stack.
+func Fold(stacks []Stack) []byte {
+	counts := make(map[string]int64, len(stacks))
+	order := make([]string, 0, len(stacks))
+
+	for _, s := range stacks {
+		key := strings.Join(s.Frames, ";")
+		if _, seen := counts[key]; !seen {
+			order = append(order, key)
+		}
+		counts[key] += s.Count
+	}
+
+	sort.Strings(order)
+
+	var buf bytes.Buffer
+	for _, key := range order {
+		fmt.Fprintf(&buf, "%s %d\n", key, counts[key])
+	}
+	return buf.Bytes()
+}
+
+// Handler serves /debug/flamegraph?seconds=N&format=svg|folded, collecting a
+// fresh CPU profile for the requested duration on every call.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	seconds := 10
+	if raw := r.URL.Query().Get("seconds"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			seconds = v
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "svg"
+	}
+
+	stacks, err := Collect(time.Duration(seconds) * time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	folded := Fold(stacks)
+
+	switch format {
+	case "folded":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(folded)
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(RenderSVG(folded))
+	default:
+		http.Error(w, "flamegraph: unknown format "+format, http.StatusBadRequest)
+	}
+}
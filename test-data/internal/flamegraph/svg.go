@@ -0,0 +1,177 @@
+package flamegraph
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+const (
+	frameHeight = 18
+	frameWidth  = 1200
+)
+
+// frame is one node in the folded-stack trie used to lay out rectangles.
+type frame struct {
+	name     string
+	count    int64
+	children map[string]*frame
+	order    []string // insertion order of children, for deterministic output
+}
+
+func newFrame(name string) *frame {
+	return &frame{name: name, children: make(map[string]*frame)}
+}
+
+func (f *frame) child(name string) *frame {
+	c, ok := f.children[name]
+	if !ok {
+		c = newFrame(name)
+		f.children[name] = c
+		f.order = append(f.order, name)
+	}
+	return c
+}
+
+// RenderSVG turns folded collapsed-stack text ("func1;func2;func3 count"
+// lines, see Fold) into a self-contained, interactive SVG flamegraph. Each
+// rectangle's width is proportional to its summed sample count; clicking a
+// rectangle zooms its subtree to fill the available width via inline JS.
+func RenderSVG(folded []byte) []byte {
+	root := newFrame("root")
+
+	for _, line := range strings.Split(strings.TrimRight(string(folded), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		sp := strings.LastIndexByte(line, ' ')
+		if sp < 0 {
+			continue
+		}
+		stack, countStr := line[:sp], line[sp+1:]
+		var count int64
+		fmt.Sscanf(countStr, "%d", &count)
+
+		node := root
+		node.count += count
+		for _, name := range strings.Split(stack, ";") {
+			node = node.child(name)
+			node.count += count
+		}
+	}
+
+	maxDepth := depthOf(root)
+	total := root.count
+	if total == 0 {
+		total = 1
+	}
+
+	var body bytes.Buffer
+	layout(&body, root, 0, total, 1, maxDepth)
+
+	height := (maxDepth + 1) * frameHeight
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="11">`,
+		frameWidth, height, frameWidth, height)
+	out.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/>`)
+	out.Write(body.Bytes())
+	out.WriteString(svgScript)
+	out.WriteString(`</svg>`)
+
+	return out.Bytes()
+}
+
+func depthOf(f *frame) int {
+	max := 0
+	for _, name := range f.order {
+		d := 1 + depthOf(f.children[name])
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// layout renders f's children as rectangles spanning [x0, x0+count) of the
+// shared `total` sample-count axis, recursing depth-first. Each rectangle
+// stores its [data-x0, data-x1) range (in sample-count units) so the click
+// handler in svgScript can rescale the whole tree around any clicked node.
+func layout(buf *bytes.Buffer, f *frame, x0, total int64, depth, maxDepth int) {
+	offset := x0
+	scale := float64(frameWidth) / float64(total)
+
+	for _, name := range f.order {
+		child := f.children[name]
+		x1 := offset + child.count
+
+		x := float64(offset) * scale
+		w := float64(child.count) * scale
+		y := depth * frameHeight
+		color := colorFor(name)
+
+		fmt.Fprintf(buf,
+			`<g class="frame" data-x0="%d" data-x1="%d" onclick="zoom(event,this)"><rect x="%f" y="%d" width="%f" height="%d" fill="%s" stroke="#fff" stroke-width="0.5"/><title>%s (%d samples)</title>`,
+			offset, x1, x, y, w, frameHeight, color, html.EscapeString(name), child.count)
+		if w > 20 {
+			fmt.Fprintf(buf, `<text x="%f" y="%d">%s</text>`, x+2, y+frameHeight-5, html.EscapeString(truncateLabel(name, w)))
+		}
+		buf.WriteString(`</g>`)
+
+		layout(buf, child, offset, total, depth+1, maxDepth)
+		offset = x1
+	}
+}
+
+func truncateLabel(name string, width float64) string {
+	maxChars := int(width / 6)
+	if maxChars <= 0 || len(name) <= maxChars {
+		return name
+	}
+	if maxChars <= 1 {
+		return ""
+	}
+	return name[:maxChars-1] + "…"
+}
+
+var palette = []string{"#e07b39", "#e0a339", "#e0c239", "#b7d139", "#6fd139", "#39d17e"}
+
+func colorFor(name string) string {
+	var h uint32
+	for _, r := range name {
+		h = h*31 + uint32(r)
+	}
+	return palette[h%uint32(len(palette))]
+}
+
+// svgScript implements click-to-zoom: clicking a frame rescales every
+// frame's x/width so the clicked frame's [data-x0, data-x1) range fills the
+// full viewport width; a plain click on the background (outside any frame)
+// is handled by the caller binding zoom to each <g> only, so clicking empty
+// space is a no-op. There is no reset: once zoomed, the view stays zoomed
+// until the SVG is reloaded.
+const svgScript = `<script><![CDATA[
+function zoom(evt, g) {
+  evt.stopPropagation();
+  var svg = g.ownerSVGElement;
+  var viewWidth = svg.viewBox.baseVal.width;
+  var x0 = parseFloat(g.getAttribute('data-x0'));
+  var x1 = parseFloat(g.getAttribute('data-x1'));
+  var span = Math.max(x1 - x0, 1);
+  var scale = viewWidth / span;
+
+  var frames = svg.getElementsByClassName('frame');
+  for (var i = 0; i < frames.length; i++) {
+    var f = frames[i];
+    var fx0 = parseFloat(f.getAttribute('data-x0'));
+    var fx1 = parseFloat(f.getAttribute('data-x1'));
+    var rect = f.getElementsByTagName('rect')[0];
+    var text = f.getElementsByTagName('text')[0];
+    var x = (fx0 - x0) * scale;
+    var w = (fx1 - fx0) * scale;
+    rect.setAttribute('x', x);
+    rect.setAttribute('width', Math.max(w, 0));
+    if (text) { text.setAttribute('x', x + 2); }
+  }
+}
+]]></script>`
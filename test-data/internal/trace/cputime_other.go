@@ -0,0 +1,9 @@
+//go:build !unix
+
+package trace
+
+import "time"
+
+// cpuTime has no portable getrusage equivalent outside unix, so CPUNanos is
+// reported as zero on these platforms and BlockNanos equals WallNanos.
+func cpuTime() time.Duration { return 0 }
@@ -0,0 +1,21 @@
+//go:build unix
+
+package trace
+
+import (
+	"syscall"
+	"time"
+)
+
+// cpuTime returns cumulative user+system CPU time for the process via
+// getrusage(RUSAGE_SELF), used to compute a span's CPU-time delta.
+func cpuTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+
+	user := time.Duration(ru.Utime.Sec)*time.Second + time.Duration(ru.Utime.Usec)*time.Microsecond
+	sys := time.Duration(ru.Stime.Sec)*time.Second + time.Duration(ru.Stime.Usec)*time.Microsecond
+	return user + sys
+}
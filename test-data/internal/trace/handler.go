@@ -0,0 +1,88 @@
+package trace
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// maxTraces bounds how many recent request traces /debug/trace/last keeps
+// around; older traces are dropped as new ones complete.
+const maxTraces = 50
+
+var (
+	mu     sync.Mutex
+	traces []*Span
+)
+
+func recordTrace(s *Span) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	traces = append(traces, s)
+	if len(traces) > maxTraces {
+		traces = traces[len(traces)-maxTraces:]
+	}
+}
+
+// Stats holds wall-vs-CPU time percentiles across the recorded traces.
+type Stats struct {
+	WallP50 int64 `json:"wall_p50_ns"`
+	WallP95 int64 `json:"wall_p95_ns"`
+	WallP99 int64 `json:"wall_p99_ns"`
+	CPUP50  int64 `json:"cpu_p50_ns"`
+	CPUP95  int64 `json:"cpu_p95_ns"`
+	CPUP99  int64 `json:"cpu_p99_ns"`
+}
+
+type lastResponse struct {
+	Traces []*Span `json:"traces"`
+	Stats  Stats   `json:"stats"`
+}
+
+// Handler serves /debug/trace/last: a JSON tree of the most recent request
+// traces plus aggregate wall-vs-CPU percentiles across them.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	snapshot := make([]*Span, len(traces))
+	copy(snapshot, traces)
+	mu.Unlock()
+
+	wall := make([]int64, len(snapshot))
+	cpu := make([]int64, len(snapshot))
+	for i, t := range snapshot {
+		wall[i] = t.WallNanos
+		cpu[i] = t.CPUNanos
+	}
+
+	resp := lastResponse{
+		Traces: snapshot,
+		Stats: Stats{
+			WallP50: percentile(wall, 50),
+			WallP95: percentile(wall, 95),
+			WallP99: percentile(wall, 99),
+			CPUP50:  percentile(cpu, 50),
+			CPUP95:  percentile(cpu, 95),
+			CPUP99:  percentile(cpu, 99),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func percentile(values []int64, p int) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := len(sorted) * p / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
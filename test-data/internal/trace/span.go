@@ -0,0 +1,102 @@
+// Package trace records request-scoped wall-time, CPU-time, block-time, and
+// allocation deltas as a tree of nested spans. CPU profiling alone misses
+// time a goroutine spends blocked (I/O, sleeps, lock contention); comparing
+// a span's WallNanos against its CPUNanos surfaces that time directly, and
+// /debug/trace/last exposes the last N request traces plus aggregate
+// percentiles so it can be inspected without a profiler attached.
+//
+// Caveats:
+//
+//   - CPUNanos/BlockNanos come from getrusage(RUSAGE_SELF), which is
+//     process-wide, not per-goroutine. Under concurrent load, a span's
+//     CPUNanos includes CPU time burned by whatever else the process was
+//     doing while the span was open, not just this request. Treat it as
+//     directionally useful with a single in-flight request, not as an
+//     exact per-request measurement under load.
+//   - Mallocs/AllocBytes require runtime.ReadMemStats, one of the more
+//     expensive runtime introspection calls, and every span pays for it
+//     twice (Start and End). It is gated behind TrackAllocs, which
+//     defaults to false; enable it only when you specifically need
+//     allocation deltas, since this demo nests up to 8 spans per request.
+package trace
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// TrackAllocs gates the runtime.ReadMemStats calls Start/End would
+// otherwise make on every span to populate Mallocs/AllocBytes. It defaults
+// to false; see the package doc for why.
+var TrackAllocs = false
+
+// Span is one node in a request trace. Start/End populate the timing and
+// allocation fields; Children are appended as nested spans are started.
+type Span struct {
+	Name       string  `json:"name"`
+	WallNanos  int64   `json:"wall_ns"`
+	CPUNanos   int64   `json:"cpu_ns"`
+	BlockNanos int64   `json:"block_ns"`
+	Mallocs    uint64  `json:"mallocs"`
+	AllocBytes uint64  `json:"alloc_bytes"`
+	Children   []*Span `json:"children,omitempty"`
+
+	parent       *Span
+	startWall    time.Time
+	startCPU     time.Duration
+	startMallocs uint64
+	startAlloc   uint64
+}
+
+type spanKeyType struct{}
+
+var spanKey spanKeyType
+
+// Start begins a new span named name, nested under whatever span (if any)
+// is already attached to ctx, and returns a context carrying the new span
+// so children started from it nest correctly.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent, _ := ctx.Value(spanKey).(*Span)
+
+	span := &Span{
+		Name:      name,
+		parent:    parent,
+		startWall: time.Now(),
+		startCPU:  cpuTime(),
+	}
+
+	if TrackAllocs {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		span.startMallocs = ms.Mallocs
+		span.startAlloc = ms.TotalAlloc
+	}
+
+	if parent != nil {
+		parent.Children = append(parent.Children, span)
+	}
+
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// End finalizes the span's timing and allocation deltas. If this is a root
+// span (no parent), the completed trace is recorded for /debug/trace/last.
+func (s *Span) End() {
+	s.WallNanos = time.Since(s.startWall).Nanoseconds()
+	s.CPUNanos = (cpuTime() - s.startCPU).Nanoseconds()
+	if block := s.WallNanos - s.CPUNanos; block > 0 {
+		s.BlockNanos = block
+	}
+
+	if TrackAllocs {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		s.Mallocs = ms.Mallocs - s.startMallocs
+		s.AllocBytes = ms.TotalAlloc - s.startAlloc
+	}
+
+	if s.parent == nil {
+		recordTrace(s)
+	}
+}
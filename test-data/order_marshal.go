@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// This file hand-writes an easyjson-style fast path for Order (and the
+// ProcessResponse envelope) so the -encoder=fast flag has something to
+// compare against reflection-driven encoding/json. It avoids map[string]
+// interface{} intermediates and interface{} boxing, and reuses buffers via
+// a sync.Pool instead of allocating one per call.
+
+var marshalBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// MarshalOrderJSON encodes order field-by-field into a pooled buffer.
+func MarshalOrderJSON(order Order) ([]byte, error) {
+	buf := marshalBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer marshalBufPool.Put(buf)
+
+	writeOrderJSON(buf, order)
+
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out, nil
+}
+
+// MarshalOrdersJSON encodes a slice of orders as a JSON array, reusing the
+// same pooled buffer for the whole slice instead of per element.
+func MarshalOrdersJSON(orders []Order) ([]byte, error) {
+	buf := marshalBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer marshalBufPool.Put(buf)
+
+	buf.WriteByte('[')
+	for i, order := range orders {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeOrderJSON(buf, order)
+	}
+	buf.WriteByte(']')
+
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out, nil
+}
+
+func writeOrderJSON(buf *bytes.Buffer, order Order) {
+	buf.WriteByte('{')
+	buf.WriteString(`"id":`)
+	writeJSONString(buf, order.ID)
+	buf.WriteString(`,"user_id":`)
+	writeJSONString(buf, order.UserID)
+	buf.WriteString(`,"amount":`)
+	buf.Write(strconv.AppendFloat(nil, order.Amount, 'f', -1, 64))
+	buf.WriteString(`,"currency":`)
+	writeJSONString(buf, order.Currency)
+	buf.WriteString(`,"status":`)
+	writeJSONString(buf, order.Status)
+	buf.WriteString(`,"timestamp":`)
+	buf.Write(strconv.AppendInt(nil, order.Timestamp, 10))
+	buf.WriteByte('}')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString escapes s per the JSON spec: '"', '\\', and all control
+// characters (< 0x20) must be escaped, the latter as \uXXXX unless they
+// have a short escape (\n, \t, \r).
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if r < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[r>>4])
+				buf.WriteByte(hexDigits[r&0xf])
+				continue
+			}
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// orderScanner walks data byte-by-byte with no encoding/json re-entry at
+// all: that's what makes UnmarshalOrderJSON actually faster than
+// json.Unmarshal instead of just a different way of calling it.
+type orderScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *orderScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *orderScanner) errf(format string, args ...interface{}) error {
+	return fmt.Errorf("order_marshal: "+format, args...)
+}
+
+func (s *orderScanner) consumeByte(b byte) error {
+	if s.pos >= len(s.data) || s.data[s.pos] != b {
+		return s.errf("expected %q at offset %d", b, s.pos)
+	}
+	s.pos++
+	return nil
+}
+
+// parseString reads a JSON string literal starting at the current '"' and
+// returns its unescaped value.
+func (s *orderScanner) parseString() (string, error) {
+	if err := s.consumeByte('"'); err != nil {
+		return "", err
+	}
+
+	start := s.pos
+	for s.pos < len(s.data) && s.data[s.pos] != '"' && s.data[s.pos] != '\\' {
+		s.pos++
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '"' {
+		// Common case: no escapes, so the string is just a slice of data.
+		str := string(s.data[start:s.pos])
+		s.pos++
+		return str, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Write(s.data[start:s.pos])
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if c == '"' {
+			s.pos++
+			return buf.String(), nil
+		}
+		if c != '\\' {
+			buf.WriteByte(c)
+			s.pos++
+			continue
+		}
+
+		s.pos++
+		if s.pos >= len(s.data) {
+			return "", s.errf("unterminated escape at offset %d", s.pos)
+		}
+		switch s.data[s.pos] {
+		case '"':
+			buf.WriteByte('"')
+		case '\\':
+			buf.WriteByte('\\')
+		case '/':
+			buf.WriteByte('/')
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if s.pos+4 >= len(s.data) {
+				return "", s.errf("truncated \\u escape at offset %d", s.pos)
+			}
+			r, err := strconv.ParseUint(string(s.data[s.pos+1:s.pos+5]), 16, 32)
+			if err != nil {
+				return "", s.errf("invalid \\u escape at offset %d: %v", s.pos, err)
+			}
+			buf.WriteRune(rune(r))
+			s.pos += 4
+		default:
+			return "", s.errf("invalid escape %q at offset %d", s.data[s.pos], s.pos)
+		}
+		s.pos++
+	}
+
+	return "", s.errf("unterminated string")
+}
+
+// scanLiteral consumes a run of bytes that can't contain a struct delimiter
+// or whitespace: a number, or true/false/null.
+func (s *orderScanner) scanLiteral() string {
+	start := s.pos
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return string(s.data[start:s.pos])
+		}
+		s.pos++
+	}
+	return string(s.data[start:s.pos])
+}
+
+func (s *orderScanner) parseFloat() (float64, error) {
+	lit := s.scanLiteral()
+	f, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return 0, s.errf("invalid number %q: %v", lit, err)
+	}
+	return f, nil
+}
+
+func (s *orderScanner) parseInt() (int64, error) {
+	lit := s.scanLiteral()
+	n, err := strconv.ParseInt(lit, 10, 64)
+	if err != nil {
+		return 0, s.errf("invalid integer %q: %v", lit, err)
+	}
+	return n, nil
+}
+
+// skipValue consumes (and discards) one JSON value of any type, so unknown
+// keys don't have to be decoded to be skipped over.
+func (s *orderScanner) skipValue() error {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return s.errf("unexpected end of input")
+	}
+
+	switch s.data[s.pos] {
+	case '"':
+		_, err := s.parseString()
+		return err
+	case '{':
+		return s.skipContainer('{', '}')
+	case '[':
+		return s.skipContainer('[', ']')
+	default:
+		if len(s.scanLiteral()) == 0 {
+			return s.errf("unexpected byte %q at offset %d", s.data[s.pos], s.pos)
+		}
+		return nil
+	}
+}
+
+// skipContainer consumes a balanced {...} or [...] span, skipping over
+// string contents so braces/brackets inside strings don't throw off depth.
+func (s *orderScanner) skipContainer(open, close byte) error {
+	if err := s.consumeByte(open); err != nil {
+		return err
+	}
+	depth := 1
+	for depth > 0 {
+		if s.pos >= len(s.data) {
+			return s.errf("unterminated %q", open)
+		}
+		switch s.data[s.pos] {
+		case '"':
+			if _, err := s.parseString(); err != nil {
+				return err
+			}
+			continue
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+		s.pos++
+	}
+	return nil
+}
+
+// UnmarshalOrderJSON decodes a single Order with a hand-rolled scanner over
+// data: no encoding/json re-entry per field, which is what makes it win
+// over json.Unmarshal (unlike decoding field-by-field via json.Decoder,
+// which re-enters the reflective decode machinery just as much as
+// json.Unmarshal does and is strictly slower).
+func UnmarshalOrderJSON(data []byte, order *Order) error {
+	s := &orderScanner{data: data}
+
+	s.skipSpace()
+	if err := s.consumeByte('{'); err != nil {
+		return err
+	}
+
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == '}' {
+		s.pos++
+		return nil
+	}
+
+	for {
+		s.skipSpace()
+		key, err := s.parseString()
+		if err != nil {
+			return err
+		}
+
+		s.skipSpace()
+		if err := s.consumeByte(':'); err != nil {
+			return err
+		}
+		s.skipSpace()
+
+		switch key {
+		case "id":
+			order.ID, err = s.parseString()
+		case "user_id":
+			order.UserID, err = s.parseString()
+		case "amount":
+			order.Amount, err = s.parseFloat()
+		case "currency":
+			order.Currency, err = s.parseString()
+		case "status":
+			order.Status, err = s.parseString()
+		case "timestamp":
+			order.Timestamp, err = s.parseInt()
+		default:
+			err = s.skipValue()
+		}
+		if err != nil {
+			return err
+		}
+
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return s.errf("unterminated object: missing '}'")
+		}
+		switch s.data[s.pos] {
+		case ',':
+			s.pos++
+			continue
+		case '}':
+			s.pos++
+			return nil
+		default:
+			return s.errf("expected ',' or '}' at offset %d", s.pos)
+		}
+	}
+}
+
+// ProcessResponse is the envelope handleProcess writes back: the processed
+// order plus which encoder produced it, useful when A/B-ing -encoder in a
+// flamegraph.
+type ProcessResponse struct {
+	Order   Order  `json:"order"`
+	Encoder string `json:"encoder"`
+}
+
+// MarshalProcessResponseJSON is the generated-style marshaler for
+// ProcessResponse, mirroring MarshalOrderJSON above.
+func MarshalProcessResponseJSON(resp ProcessResponse) ([]byte, error) {
+	buf := marshalBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer marshalBufPool.Put(buf)
+
+	buf.WriteByte('{')
+	buf.WriteString(`"order":`)
+	writeOrderJSON(buf, resp.Order)
+	buf.WriteString(`,"encoder":`)
+	writeJSONString(buf, resp.Encoder)
+	buf.WriteByte('}')
+
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestMarshalOrderJSONEscapesControlChars(t *testing.T) {
+	order := Order{ID: "line1\nline2\ttab", UserID: "USER-1"}
+
+	body, err := MarshalOrderJSON(order)
+	if err != nil {
+		t.Fatalf("MarshalOrderJSON: %v", err)
+	}
+
+	var roundTripped Order
+	if err := UnmarshalOrderJSON(body, &roundTripped); err != nil {
+		t.Fatalf("UnmarshalOrderJSON(%s): %v", body, err)
+	}
+
+	if roundTripped.ID != order.ID {
+		t.Errorf("ID round-trip = %q, want %q", roundTripped.ID, order.ID)
+	}
+}
+
+func TestUnmarshalOrderJSONRejectsTruncatedInput(t *testing.T) {
+	var order Order
+	if err := UnmarshalOrderJSON([]byte(`{"id":"ORD-1"`), &order); err == nil {
+		t.Fatal("UnmarshalOrderJSON(truncated object): got nil error, want error")
+	}
+}
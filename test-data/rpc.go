@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// JSON-RPC 2.0 standard error codes, see
+// https://www.jsonrpc.org/specification#error_object.
+const (
+	rpcErrParseError     = -32700
+	rpcErrInvalidRequest = -32600
+	rpcErrMethodNotFound = -32601
+	rpcErrInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// handleRPC implements a JSON-RPC 2.0 endpoint over orders.list,
+// orders.process, and orders.processBatch, accepting either a single
+// request object or a batch array per the spec. Notifications (requests
+// with no "id") produce no entry in the response.
+func handleRPC(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeRPCResponse(w, &rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcErrParseError, Message: "parse error"}})
+		return
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(raw, &reqs); err != nil {
+			writeRPCResponse(w, &rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcErrInvalidRequest, Message: "invalid request"}})
+			return
+		}
+
+		responses := make([]rpcResponse, 0, len(reqs))
+		for _, req := range reqs {
+			if resp := dispatchRPC(r.Context(), req); resp != nil {
+				responses = append(responses, *resp)
+			}
+		}
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(responses)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		writeRPCResponse(w, &rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcErrInvalidRequest, Message: "invalid request"}})
+		return
+	}
+
+	resp := dispatchRPC(r.Context(), req)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeRPCResponse(w, resp)
+}
+
+func writeRPCResponse(w http.ResponseWriter, resp *rpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// dispatchRPC runs a single JSON-RPC request and returns its response, or
+// nil if req is a notification (no "id", per spec no response is sent).
+func dispatchRPC(ctx context.Context, req rpcRequest) *rpcResponse {
+	respond := func(result interface{}, rpcErr *rpcError) *rpcResponse {
+		if len(req.ID) == 0 {
+			return nil
+		}
+		return &rpcResponse{JSONRPC: "2.0", Result: result, Error: rpcErr, ID: req.ID}
+	}
+
+	switch req.Method {
+	case "orders.list":
+		var params struct {
+			Count int `json:"count"`
+		}
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return respond(nil, &rpcError{Code: rpcErrInvalidParams, Message: "invalid params"})
+			}
+		}
+		if params.Count <= 0 {
+			params.Count = 100
+		}
+		return respond(generateOrders(params.Count), nil)
+
+	case "orders.process":
+		var order Order
+		if err := json.Unmarshal(req.Params, &order); err != nil {
+			return respond(nil, &rpcError{Code: rpcErrInvalidParams, Message: "invalid params"})
+		}
+		return respond(processOrder(ctx, order), nil)
+
+	case "orders.processBatch":
+		var params struct {
+			Orders []Order `json:"orders"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return respond(nil, &rpcError{Code: rpcErrInvalidParams, Message: "invalid params"})
+		}
+		results := make([]Order, len(params.Orders))
+		for i, order := range params.Orders {
+			results[i] = processOrder(ctx, order)
+		}
+		return respond(results, nil)
+
+	default:
+		return respond(nil, &rpcError{Code: rpcErrMethodNotFound, Message: "method not found"})
+	}
+}
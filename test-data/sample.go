@@ -1,15 +1,30 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Rperry2174/pyroscope-vscode-extension/test-data/internal/flamegraph"
+	"github.com/Rperry2174/pyroscope-vscode-extension/test-data/internal/trace"
+	"github.com/pyroscope-io/client/pyroscope"
 )
 
+// encoder selects between the reflection-driven encoding/json path and the
+// hand-written fast path in order_marshal.go, so the two can be A/B'd in a
+// flamegraph: `-encoder=stdlib` (default) or `-encoder=fast`.
+var encoder = flag.String("encoder", "stdlib", "json encoder to use for Order payloads: stdlib|fast")
+
 type Order struct {
 	ID        string  `json:"id"`
 	UserID    string  `json:"user_id"`
@@ -20,76 +35,209 @@ type Order struct {
 }
 
 func main() {
+	flag.Parse()
+
+	startPyroscope()
+
 	http.HandleFunc("/api/orders", handleOrders)
 	http.HandleFunc("/api/process", handleProcess)
+	http.HandleFunc("/rpc", handleRPC)
+
+	http.HandleFunc("/debug/pprof/", pprof.Index)
+	http.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	http.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	http.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	http.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	http.HandleFunc("/debug/flamegraph", flamegraph.Handler)
+	http.HandleFunc("/debug/trace/last", trace.Handler)
 
 	log.Println("Server starting on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
+// startPyroscope starts the pyroscope agent, which pushes this process's
+// profiles to PYROSCOPE_SERVER_ADDRESS; the functions wrapped in
+// pyroscope.TagWrapper below show up there as labeled scopes.
+func startPyroscope() {
+	serverAddress := os.Getenv("PYROSCOPE_SERVER_ADDRESS")
+	if serverAddress == "" {
+		serverAddress = "http://localhost:4040"
+	}
+
+	// ProfileTypes is left unset: the client defaults it to cpu + alloc_objects
+	// + alloc_space + inuse_objects + inuse_space, which is what we want here.
+	_, err := pyroscope.Start(pyroscope.Config{
+		ApplicationName: "pyroscope-vscode-extension.demo-server",
+		ServerAddress:   serverAddress,
+		Logger:          pyroscope.StandardLogger,
+	})
+	if err != nil {
+		log.Printf("pyroscope: failed to start agent: %v", err)
+	}
+}
+
 func handleOrders(w http.ResponseWriter, r *http.Request) {
-	orders := generateOrders(100)
-	json.NewEncoder(w).Encode(orders)
+	var orders []Order
+	if poolingEnabled() {
+		ptr := orderSlicePool.Get().(*[]Order)
+		orders = generateOrdersInto((*ptr)[:0], 100)
+		defer func() {
+			*ptr = orders[:0]
+			orderSlicePool.Put(ptr)
+		}()
+	} else {
+		orders = generateOrders(100)
+	}
+
+	if *encoder == "fast" {
+		body, _ := MarshalOrdersJSON(orders)
+		w.Write(body)
+		return
+	}
+	encodeJSON(w, orders)
 }
 
 func handleProcess(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.Start(r.Context(), "handleProcess")
+	defer span.End()
+
 	var order Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+	var err error
+	if *encoder == "fast" {
+		body, readErr := io.ReadAll(r.Body)
+		if readErr != nil {
+			http.Error(w, readErr.Error(), http.StatusBadRequest)
+			return
+		}
+		err = UnmarshalOrderJSON(body, &order)
+	} else {
+		err = decodeOrder(r.Body, &order)
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// This is a hot spot - processOrder is expensive
-	result := processOrder(order)
-	json.NewEncoder(w).Encode(result)
+	result := processOrder(ctx, order)
+	response := ProcessResponse{Order: result, Encoder: *encoder}
+
+	if *encoder == "fast" {
+		body, _ := MarshalProcessResponseJSON(response)
+		w.Write(body)
+		return
+	}
+	encodeJSON(w, response)
 }
 
 // HOT SPOT: This function consumes 34.5% of CPU time
-func processOrder(order Order) Order {
-	// Simulate expensive validation
-	if !validateInput(order) {
-		order.Status = "invalid"
-		return order
-	}
+func processOrder(ctx context.Context, order Order) Order {
+	ctx, span := trace.Start(ctx, "processOrder")
+	defer span.End()
+
+	pyroscope.TagWrapper(ctx, pyroscope.Labels("function", "processOrder"), func(ctx context.Context) {
+		// Simulate expensive validation
+		if !validateInput(ctx, order) {
+			order.Status = "invalid"
+			return
+		}
 
-	// Simulate database operations
-	order.Amount = calculateTotal(order)
+		// Simulate database operations
+		order.Amount = calculateTotal(ctx, order)
 
-	// Simulate external API calls
-	data := fetchData(order.UserID)
-	processData(data)
+		// Simulate external API calls
+		data := fetchData(ctx, order.UserID)
+		processData(ctx, data)
 
-	order.Status = "processed"
-	order.Timestamp = time.Now().Unix()
+		order.Status = "processed"
+		order.Timestamp = time.Now().Unix()
+	})
 
 	return order
 }
 
-// HOT SPOT: 12.3% of CPU time - validation is slow
-func validateInput(order Order) bool {
-	// Expensive string operations
-	if len(order.ID) == 0 || len(order.UserID) == 0 {
-		return false
-	}
+// formerly HOT SPOT at 12.3% of CPU time: the validation key used to be
+// rebuilt 1000 times with fmt.Sprintf and discarded every time. It's now
+// built once with a pre-grown strings.Builder.
+func validateInput(ctx context.Context, order Order) bool {
+	ctx, span := trace.Start(ctx, "validateInput")
+	defer span.End()
 
-	// Simulate complex validation logic
-	for i := 0; i < 1000; i++ {
-		_ = fmt.Sprintf("%s-%s-%d", order.ID, order.UserID, i)
-	}
+	valid := false
+
+	pyroscope.TagWrapper(ctx, pyroscope.Labels("function", "validateInput"), func(ctx context.Context) {
+		if len(order.ID) == 0 || len(order.UserID) == 0 {
+			return
+		}
+
+		var key strings.Builder
+		key.Grow(len(order.ID) + len(order.UserID) + 8)
+		key.WriteString(order.ID)
+		key.WriteByte('-')
+		key.WriteString(order.UserID)
+		key.WriteByte('-')
+		key.Write(strconv.AppendInt(nil, 0, 10))
+		_ = key.String()
 
-	return order.Amount > 0
+		valid = order.Amount > 0
+	})
+
+	return valid
 }
 
-// HOT SPOT: 18.7% of CPU time - calculations are intensive
-func calculateTotal(order Order) float64 {
-	total := order.Amount
+// calcTotalIterations and calcTotalGrowthRate are the fixed N and a from the
+// calculateTotal recurrence: t_{n+1} = a*t_n + a*0.001*n, t_0 = order.Amount.
+const (
+	calcTotalIterations = 5000
+	calcTotalGrowthRate = 1.0001
+)
+
+// calcTotalAN and calcTotalSum close the recurrence above in O(1):
+// t_N = a^N*t_0 + sum_{j=0}^{N-1} a^{N-j}*0.001*j, precomputed once here
+// since N and a are both constants.
+var (
+	calcTotalAN  float64
+	calcTotalSum float64
+)
 
-	// Simulate expensive calculations
-	for i := 0; i < 5000; i++ {
-		total += float64(i) * 0.001
-		total = total * 1.0001
+func init() {
+	calcTotalAN = 1
+	for i := 0; i < calcTotalIterations; i++ {
+		calcTotalAN *= calcTotalGrowthRate
 	}
 
+	aPow := calcTotalAN // a^(N-j), starts at j=0 so a^N
+	for j := 0; j < calcTotalIterations; j++ {
+		calcTotalSum += aPow * 0.001 * float64(j)
+		aPow /= calcTotalGrowthRate
+	}
+}
+
+// formerly HOT SPOT at 18.7% of CPU time: the 5000-iteration loop was a
+// linear recurrence with a constant growth rate, so it closes analytically
+// via calcTotalAN/calcTotalSum instead of iterating. See
+// TestCalculateTotalEquivalence for a check against the original loop.
+func calculateTotal(ctx context.Context, order Order) float64 {
+	ctx, span := trace.Start(ctx, "calculateTotal")
+	defer span.End()
+
+	var total float64
+
+	pyroscope.TagWrapper(ctx, pyroscope.Labels("function", "calculateTotal"), func(ctx context.Context) {
+		total = calculateTotalClosed(order.Amount)
+	})
+
+	return total
+}
+
+// calculateTotalClosed is the O(1) math calculateTotal applies, pulled out
+// on its own so it can be benchmarked head-to-head against
+// calculateTotalNaive without tracing/pyroscope overhead skewing the
+// comparison (see calculate_total_test.go).
+func calculateTotalClosed(amount float64) float64 {
+	total := calcTotalAN*amount + calcTotalSum
+
 	// Apply fees
 	fee := total * 0.029
 	total += fee
@@ -97,44 +245,74 @@ func calculateTotal(order Order) float64 {
 	return total
 }
 
+// fetchDataSleep is how long fetchData simulates a slow database query for;
+// it's a var rather than a const so benchmarks covering many orders (see
+// BenchmarkRESTLoop/BenchmarkRPCBatch) can turn it down instead of actually
+// sleeping 10ms per order.
+var fetchDataSleep = 10 * time.Millisecond
+
 // HOT SPOT: 9.8% of CPU time - I/O bound
-func fetchData(userID string) map[string]interface{} {
-	// Simulate slow database query
-	time.Sleep(10 * time.Millisecond)
+func fetchData(ctx context.Context, userID string) map[string]interface{} {
+	ctx, span := trace.Start(ctx, "fetchData")
+	defer span.End()
+
+	var data map[string]interface{}
 
-	data := make(map[string]interface{})
-	data["user_id"] = userID
-	data["preferences"] = generatePreferences()
-	data["history"] = generateHistory()
+	pyroscope.TagWrapper(ctx, pyroscope.Labels("function", "fetchData"), func(ctx context.Context) {
+		// Simulate slow database query
+		time.Sleep(fetchDataSleep)
+
+		data = getDataMap()
+		data["user_id"] = userID
+		data["preferences"] = generatePreferences()
+		data["history"] = generateHistory()
+	})
 
 	return data
 }
 
-func processData(data map[string]interface{}) {
+// processData is the last consumer of data, so it returns it to
+// dataMapPool once done; callers must not touch data after this returns.
+func processData(ctx context.Context, data map[string]interface{}) {
+	ctx, span := trace.Start(ctx, "processData")
+	defer span.End()
+
 	// Simulate data processing
-	_ = parseJSON(data)
-	writeLog(data)
+	_ = parseJSON(ctx, data)
+	writeLog(ctx, data)
+
+	putDataMap(data)
 }
 
 // HOT SPOT: 7.1% of CPU time
-func parseJSON(data map[string]interface{}) []byte {
-	result, _ := json.Marshal(data)
+func parseJSON(ctx context.Context, data map[string]interface{}) []byte {
+	ctx, span := trace.Start(ctx, "parseJSON")
+	defer span.End()
 
-	// Simulate parsing overhead
-	for i := 0; i < 100; i++ {
-		var temp map[string]interface{}
-		json.Unmarshal(result, &temp)
-	}
+	var result []byte
+
+	pyroscope.TagWrapper(ctx, pyroscope.Labels("function", "parseJSON"), func(ctx context.Context) {
+		result, _ = json.Marshal(data)
+
+		// Simulate parsing overhead
+		for i := 0; i < 100; i++ {
+			var temp map[string]interface{}
+			json.Unmarshal(result, &temp)
+		}
+	})
 
 	return result
 }
 
 // HOT SPOT: 4.5% of CPU time
-func writeLog(data map[string]interface{}) {
+func writeLog(ctx context.Context, data map[string]interface{}) {
+	_, span := trace.Start(ctx, "writeLog")
+	defer span.End()
+
 	logEntry := fmt.Sprintf("[%s] Processing: %v", time.Now().Format(time.RFC3339), data)
 
 	// Simulate log writing
-	_ = io.Discard.Write([]byte(logEntry))
+	_, _ = io.Discard.Write([]byte(logEntry))
 }
 
 func formatOutput(data interface{}) string {
@@ -145,17 +323,21 @@ func formatOutput(data interface{}) string {
 func generateOrders(count int) []Order {
 	orders := make([]Order, count)
 	for i := 0; i < count; i++ {
-		orders[i] = Order{
-			ID:       fmt.Sprintf("ORD-%d", i),
-			UserID:   fmt.Sprintf("USER-%d", rand.Intn(100)),
-			Amount:   rand.Float64() * 1000,
-			Currency: "USD",
-			Status:   "pending",
-		}
+		orders[i] = randomOrder(i)
 	}
 	return orders
 }
 
+func randomOrder(i int) Order {
+	return Order{
+		ID:       fmt.Sprintf("ORD-%d", i),
+		UserID:   fmt.Sprintf("USER-%d", rand.Intn(100)),
+		Amount:   rand.Float64() * 1000,
+		Currency: "USD",
+		Status:   "pending",
+	}
+}
+
 func generatePreferences() map[string]string {
 	return map[string]string{
 		"currency":     "USD",
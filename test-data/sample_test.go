@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// BenchmarkRESTLoop issues one POST /api/process per order, the pre-/rpc
+// baseline: size orders means size HTTP round-trips, each with its own JSON
+// decode.
+func BenchmarkRESTLoop(b *testing.B) {
+	prevSleep := fetchDataSleep
+	fetchDataSleep = 0
+	defer func() { fetchDataSleep = prevSleep }()
+
+	for _, size := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("n=%d", size), func(b *testing.B) {
+			orders := generateOrdersRC(size, 0)
+			bodies := make([][]byte, size)
+			for i, o := range orders {
+				body, err := json.Marshal(o)
+				if err != nil {
+					b.Fatal(err)
+				}
+				bodies[i] = body
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, body := range bodies {
+					req := httptest.NewRequest("POST", "/api/process", bytes.NewReader(body))
+					rec := httptest.NewRecorder()
+					handleProcess(rec, req)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkHandleProcessAllocs compares per-request allocations with
+// sync.Pool reuse on vs off; run with -benchmem to see the allocs/op drop.
+func BenchmarkHandleProcessAllocs(b *testing.B) {
+	prevPool := *poolMode
+	defer func() { *poolMode = prevPool }()
+
+	prevSleep := fetchDataSleep
+	fetchDataSleep = 0
+	defer func() { fetchDataSleep = prevSleep }()
+
+	// generateOrdersRC(1, 0)[0] would have Amount == 0 (i == 0), which
+	// validateInput rejects before fetchData/dataMapPool ever run - take
+	// index 1 instead so this benchmark actually exercises the pool.
+	order := generateOrdersRC(2, 0)[1]
+	body, err := json.Marshal(order)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, mode := range []string{"off", "on"} {
+		b.Run(mode, func(b *testing.B) {
+			*poolMode = mode
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest("POST", "/api/process", bytes.NewReader(body))
+				rec := httptest.NewRecorder()
+				handleProcess(rec, req)
+			}
+		})
+	}
+}
+
+// BenchmarkRPCBatch issues a single POST /rpc batch of orders.process calls
+// of the same size, to show the JSON decoding and HTTP framing overhead
+// BenchmarkRESTLoop pays per order collapses into one round-trip.
+func BenchmarkRPCBatch(b *testing.B) {
+	prevSleep := fetchDataSleep
+	fetchDataSleep = 0
+	defer func() { fetchDataSleep = prevSleep }()
+
+	for _, size := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("n=%d", size), func(b *testing.B) {
+			orders := generateOrdersRC(size, 0)
+			reqs := make([]rpcRequest, size)
+			for i, o := range orders {
+				params, err := json.Marshal(o)
+				if err != nil {
+					b.Fatal(err)
+				}
+				reqs[i] = rpcRequest{JSONRPC: "2.0", Method: "orders.process", Params: params, ID: json.RawMessage(fmt.Sprintf("%d", i))}
+			}
+			body, err := json.Marshal(reqs)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest("POST", "/rpc", bytes.NewReader(body))
+				rec := httptest.NewRecorder()
+				handleRPC(rec, req)
+			}
+		})
+	}
+}
+
+// generateOrdersRC builds a rows x cols fixture: rows is the number of
+// orders (long), cols pads ID/UserID to simulate a wide row. Mirrors the
+// DataStation-style long/wide fixture knobs used to A/B the two encoders
+// below on both narrow and wide payloads.
+func generateOrdersRC(rows, cols int) []Order {
+	orders := make([]Order, rows)
+	pad := strings.Repeat("x", cols)
+	for i := 0; i < rows; i++ {
+		orders[i] = Order{
+			ID:       fmt.Sprintf("ORD-%d-%s", i, pad),
+			UserID:   fmt.Sprintf("USER-%d-%s", i%100, pad),
+			Amount:   float64(i) * 1.5,
+			Currency: "USD",
+			Status:   "pending",
+		}
+	}
+	return orders
+}
+
+func BenchmarkHandleProcess(b *testing.B) {
+	widths := []struct {
+		name string
+		cols int
+	}{
+		{"narrow", 0},
+		{"wide", 512},
+	}
+
+	prevEncoder := *encoder
+	defer func() { *encoder = prevEncoder }()
+
+	prevSleep := fetchDataSleep
+	fetchDataSleep = 0
+	defer func() { fetchDataSleep = prevSleep }()
+
+	for _, enc := range []string{"stdlib", "fast"} {
+		for _, width := range widths {
+			b.Run(enc+"/"+width.name, func(b *testing.B) {
+				*encoder = enc
+				// index 1, not 0: generateOrdersRC sets Amount = i*1.5, so
+				// index 0 is always Amount == 0 and validateInput would
+				// short-circuit processOrder to "invalid" before encoding.
+				order := generateOrdersRC(2, width.cols)[1]
+				body, err := json.Marshal(order)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					req := httptest.NewRequest("POST", "/api/process", bytes.NewReader(body))
+					rec := httptest.NewRecorder()
+					handleProcess(rec, req)
+				}
+			})
+		}
+	}
+}